@@ -0,0 +1,125 @@
+// Copyright (c) 2025 Otto
+// Лицензия: MIT (см. LICENSE)
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildOptions описывает правила фильтрации и ограничение глубины, с которыми
+// buildNode обходит дерево. maxDepth == -1 означает «без ограничения»
+type buildOptions struct {
+	include         []string // glob-шаблоны: если список непуст, проходят только совпавшие записи
+	exclude         []string // glob-шаблоны, которые нужно исключить из дерева
+	maxDepth        int      // максимальная глубина вложенности поддиректорий, -1 — без ограничения
+	followGitignore bool     // подключать ли .gitignore, встреченные при обходе
+}
+
+// defaultBuildOptions возвращает опции обхода без какой-либо фильтрации
+func defaultBuildOptions() *buildOptions {
+	return &buildOptions{maxDepth: -1}
+}
+
+// globMatch сообщает, подходит ли запись под шаблон pattern. Шаблоны без "/"
+// сравниваются только с базовым именем (base), шаблоны с "/" — с относительным
+// путём от корня (relPath). "**" соответствует произвольному, в том числе
+// нулевому, числу сегментов пути
+func globMatch(pattern, base, relPath string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := filepath.Match(pattern, base)
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(filepath.ToSlash(relPath), "/"))
+}
+
+// matchSegments рекурсивно сопоставляет сегменты шаблона и пути, обрабатывая
+// "**" как ноль или более сегментов подряд
+func matchSegments(pat, name []string) bool {
+	if len(pat) == 0 {
+		return len(name) == 0
+	}
+	if pat[0] == "**" {
+		if matchSegments(pat[1:], name) {
+			// "**" съедает ноль сегментов
+			return true
+		}
+		if len(name) == 0 {
+			return false
+		}
+		// "**" съедает ещё один сегмент и пробует снова
+		return matchSegments(pat, name[1:])
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pat[0], name[0])
+	if !ok {
+		return false
+	}
+	return matchSegments(pat[1:], name[1:])
+}
+
+// matchRules прогоняет rules по порядку в духе .gitignore: каждый следующий
+// шаблон может как добавить совпадение, так и снять его, если он начинается
+// с "!". Итоговое значение — результат после применения последнего
+// подошедшего правила
+func matchRules(base, relPath string, rules []string) bool {
+	matched := false
+	for _, rule := range rules {
+		negate := strings.HasPrefix(rule, "!")
+		pattern := strings.TrimPrefix(rule, "!")
+		if pattern == "" {
+			continue
+		}
+		if globMatch(pattern, base, relPath) {
+			matched = !negate
+		}
+	}
+	return matched
+}
+
+// isFilteredOut решает, нужно ли пропустить запись с именем base (и
+// относительным путём relPath) на основании флагов --exclude/--include.
+// --exclude может отсекать целые поддиректории, а --include — нет: включающие
+// шаблоны почти всегда описывают расширения файлов (--include=*.go), и имя
+// директории им заведомо не соответствует, поэтому --include применяется
+// только к листьям (файлам), а директории всегда обходятся дальше
+func isFilteredOut(base, relPath string, isDir bool, opts *buildOptions) bool {
+	if len(opts.exclude) > 0 && matchRules(base, relPath, opts.exclude) {
+		return true
+	}
+	if !isDir && len(opts.include) > 0 && !matchRules(base, relPath, opts.include) {
+		return true
+	}
+	return false
+}
+
+// loadGitignoreRules читает .gitignore в директории dir, если он существует,
+// и возвращает его строки как список шаблонов (комментарии и пустые строки
+// отбрасываются). Отсутствие файла не считается ошибкой
+func loadGitignoreRules(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, line)
+	}
+	return rules, scanner.Err()
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2025 Otto
+// Лицензия: MIT (см. LICENSE)
+
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// jsonNode — JSON-представление Node: та же форма (name/path/type/size/mode/
+// mtime/children), что используется, например, в файловом API b3log/wide,
+// чтобы существующие фронтенды могли читать вывод FolderTree без переделок
+type jsonNode struct {
+	Name     string      `json:"name"`
+	Path     string      `json:"path"`
+	Type     string      `json:"type"` // "f" для файла, "d" для директории
+	Size     int64       `json:"size"`
+	Mode     string      `json:"mode"`
+	ModTime  string      `json:"mtime"`
+	Children []*jsonNode `json:"children,omitempty"`
+}
+
+// renderJSON формирует стабильное JSON-представление дерева, пригодное для
+// программной обработки (в отличие от renderUnicodeTree/renderMarkdown/renderHTML,
+// рассчитанных на чтение человеком)
+func renderJSON(root *Node) string {
+	data, err := json.MarshalIndent(toJSONNode(root), "", "  ")
+	if err != nil {
+		// Сериализация дерева Node в jsonNode не должна давать ошибок маршалинга
+		return "{}"
+	}
+	return string(data)
+}
+
+// toJSONNode рекурсивно переводит Node в jsonNode
+func toJSONNode(n *Node) *jsonNode {
+	jn := &jsonNode{
+		Name:    n.Name,
+		Path:    n.Path,
+		Size:    n.Size,
+		Mode:    n.Mode.String(),
+		ModTime: n.ModTime.Format(time.RFC3339),
+	}
+	if n.IsDir {
+		jn.Type = "d"
+	} else {
+		jn.Type = "f"
+	}
+	for _, c := range n.Children {
+		jn.Children = append(jn.Children, toJSONNode(c))
+	}
+	return jn
+}
@@ -7,18 +7,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const CurrentVersion = "28.10.25" // Текущая версия FolderTree в формате "дд.мм.гг"
 
 // Node структура представляет собой узел в дереве файловой системы
 type Node struct {
-	Name     string  // Cодержит имя файла или директории
-	Path     string  // Cодержит полный путь до узла
-	IsDir    bool    // Указывает, является ли узел директорией
-	Children []*Node // Содержит список дочерних узлов
+	Name     string      // Cодержит имя файла или директории
+	Path     string      // Cодержит полный путь до узла
+	IsDir    bool        // Указывает, является ли узел директорией
+	Size     int64       // Размер файла в байтах; для директорий — сумма размеров всех потомков
+	Mode     os.FileMode // Права доступа и тип файла (как у os.FileInfo.Mode)
+	ModTime  time.Time   // Время последнего изменения
+	Err      error       // Заполняется, если директорию не удалось прочитать (вместо молчаливого пропуска)
+	Children []*Node     // Содержит список дочерних узлов
 }
 
 func main() {
@@ -34,7 +41,15 @@ func main() {
 		os.Exit(1)
 	}
 
-	dirPath := strings.Join(os.Args[1:], " ") // Собирает весь путь, даже если в нём есть пробелы
+	args, flags, opts := parseFlags(os.Args[1:]) // Вынимает флаги (--serve, --exclude, --show-meta, ...) из аргументов
+
+	if len(args) < 1 {
+		// Путь к директории обязателен, даже если указан --serve
+		fmt.Println("Использование: укажите путь к директории как аргумент.")
+		os.Exit(1)
+	}
+
+	dirPath := strings.Join(args, " ") // Собирает весь путь, даже если в нём есть пробелы
 
 	info, err := os.Stat(dirPath)
 	if err != nil {
@@ -49,6 +64,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	if flags.serveAddr != "" {
+		// Вместо записи файлов поднимает HTTP-сервер и строит дерево по запросу
+		if err := runServer(flags.serveAddr, dirPath, opts, flags); err != nil {
+			fmt.Printf("Ошибка запуска сервера: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	exePath, err := os.Executable()
 	if err != nil {
 		// Сообщает об ошибке, если невозможно определить путь исполняемого файла
@@ -57,88 +81,253 @@ func main() {
 	}
 	exeDir := filepath.Dir(exePath)
 
-	root, err := buildNode(dirPath)
+	root, err := buildNode(dirPath, opts)
 	if err != nil {
 		// Останавливает выполнение, если не удалось построить дерево из-за ошибки доступа или пути
 		fmt.Printf("Ошибка построения дерева: %v\n", err)
 		os.Exit(1)
 	}
 
+	var results SearchResults
+	if flags.grep != nil {
+		// Ищет совпадения --grep по уже построенному (и отфильтрованному) дереву
+		results = searchTree(root, flags.grep, flags.grepExts)
+	}
+
 	// --- Рендеринг и запись файлов ---
+	// Набор всех поддерживаемых форматов; --format сужает его до нужных пользователю
+	outputs := []struct {
+		format   string // ключ для --format
+		fileName string
+		render   func() (string, error)
+	}{
+		{"txt", "Древо папок.txt", func() (string, error) { return renderUnicodeTree(root, flags.showMeta, results), nil }},
+		{"md", "Древо папок (Markdown).md", func() (string, error) { return renderMarkdown(root, flags.showMeta, results), nil }},
+		{"html", "Древо папок (WEB).html", func() (string, error) { return renderHTML(root, flags.showMeta, results, flags.templatePath) }},
+		{"json", "Древо папок.json", func() (string, error) { return renderJSON(root), nil }},
+	}
 
-	unicodeText := renderUnicodeTree(root)
-	err = os.WriteFile(filepath.Join(exeDir, "Древо папок.txt"), []byte(unicodeText), 0644)
-	if err != nil {
-		// Игнорирует ошибку, если запись первого файла не удалась
-		fmt.Printf("Ошибка записи 'Древо папок.txt': %v\n", err)
+	fmt.Printf("Созданы файлы по пути \"%s\":\n", exeDir)
+	for _, o := range outputs {
+		if !flags.formatEnabled(o.format) {
+			continue
+		}
+		text, renderErr := o.render()
+		if renderErr != nil {
+			// Игнорирует ошибку, если отрисовка конкретного формата не удалась, и переходит к следующему
+			fmt.Printf("Ошибка рендеринга '%s': %v\n", o.fileName, renderErr)
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(exeDir, o.fileName), []byte(text), 0644); err != nil {
+			// Игнорирует ошибку, если запись конкретного файла не удалась, и переходит к следующему
+			fmt.Printf("Ошибка записи '%s': %v\n", o.fileName, err)
+			continue
+		}
+		fmt.Println(" - " + o.fileName)
 	}
+}
 
-	mdText := renderMarkdown(root)
-	err = os.WriteFile(filepath.Join(exeDir, "Древо папок (Markdown).md"), []byte(mdText), 0644)
-	if err != nil {
-		// Игнорирует ошибку, если запись второго файла не удалась
-		fmt.Printf("Ошибка записи 'Древо папок (Markdown).md': %v\n", err)
+// cliFlags собирает значения флагов, не относящихся напрямую к фильтрации
+// обхода (buildOptions), но влияющих на то, как и что FolderTree выводит
+type cliFlags struct {
+	serveAddr    string         // адрес для --serve; пустая строка означает, что флаг не указан
+	showMeta     bool           // --show-meta: выводить размер/права/дату изменения в рендерах
+	formats      []string       // --format=txt,md,html,json: какие файлы записывать (по умолчанию — все)
+	grep         *regexp.Regexp // --grep <regex>: шаблон поиска по содержимому файлов; nil — поиск выключен
+	grepExts     []string       // --grep-ext .go,.md: ограничивает --grep указанными расширениями
+	templatePath string         // --template <path>: пользовательский шаблон для renderHTML; пусто — встроенный
+}
+
+// formatEnabled сообщает, нужно ли записывать файл формата format (txt/md/html/json)
+func (f *cliFlags) formatEnabled(format string) bool {
+	for _, want := range f.formats {
+		if want == format {
+			return true
+		}
 	}
+	return false
+}
 
-	htmlText := renderHTML(root)
-	err = os.WriteFile(filepath.Join(exeDir, "Древо папок (WEB).html"), []byte(htmlText), 0644)
-	if err != nil {
-		// Игнорирует ошибку, если запись третьего файла не удалась
-		fmt.Printf("Ошибка записи 'Древо папок (WEB).html': %v\n", err)
+// allFormats перечисляет форматы, которые FolderTree пишет на диск, если
+// --format не указан
+var allFormats = []string{"txt", "md", "html", "json"}
+
+// parseFlags вынимает из аргументов все флаги, известные FolderTree
+// (--serve, --exclude, --include, --max-depth, --follow-gitignore,
+// --show-meta, --format, --grep, --grep-ext, --template), и возвращает
+// оставшиеся аргументы (путь к директории), значения плоских флагов и опции
+// обхода дерева
+func parseFlags(args []string) ([]string, *cliFlags, *buildOptions) {
+	const defaultAddr = ":8080"
+	rest := make([]string, 0, len(args))
+	flags := &cliFlags{formats: allFormats}
+	opts := defaultBuildOptions()
+
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case strings.EqualFold(a, "--serve"):
+			flags.serveAddr = defaultAddr
+		case strings.HasPrefix(strings.ToLower(a), "--serve="):
+			// Срез по исходной строке, чтобы не терять регистр в самом адресе
+			flags.serveAddr = a[len("--serve="):]
+			if flags.serveAddr == "" {
+				flags.serveAddr = defaultAddr
+			}
+		case strings.HasPrefix(strings.ToLower(a), "--exclude="):
+			opts.exclude = append(opts.exclude, a[len("--exclude="):])
+		case strings.HasPrefix(strings.ToLower(a), "--include="):
+			opts.include = append(opts.include, a[len("--include="):])
+		case strings.HasPrefix(strings.ToLower(a), "--max-depth="):
+			if n, err := strconv.Atoi(a[len("--max-depth="):]); err == nil && n >= 0 {
+				opts.maxDepth = n
+			}
+		case strings.EqualFold(a, "--follow-gitignore"):
+			opts.followGitignore = true
+		case strings.EqualFold(a, "--show-meta"):
+			flags.showMeta = true
+		case strings.HasPrefix(strings.ToLower(a), "--format="):
+			// Список форматов через запятую заменяет набор по умолчанию целиком
+			var requested []string
+			for _, f := range strings.Split(a[len("--format="):], ",") {
+				f = strings.ToLower(strings.TrimSpace(f))
+				if f != "" {
+					requested = append(requested, f)
+				}
+			}
+			if len(requested) > 0 {
+				flags.formats = requested
+			}
+		case strings.EqualFold(a, "--grep"):
+			// Шаблон передаётся отдельным аргументом: --grep <regex>
+			if i+1 < len(args) {
+				i++
+				if re, err := regexp.Compile(args[i]); err == nil {
+					flags.grep = re
+				} else {
+					fmt.Printf("Ошибка в регулярном выражении --grep: %v\n", err)
+				}
+			}
+		case strings.HasPrefix(strings.ToLower(a), "--grep-ext="):
+			for _, ext := range strings.Split(a[len("--grep-ext="):], ",") {
+				ext = strings.TrimSpace(ext)
+				if ext != "" {
+					flags.grepExts = append(flags.grepExts, ext)
+				}
+			}
+		case strings.EqualFold(a, "--template"):
+			// Путь передаётся отдельным аргументом: --template <path>
+			if i+1 < len(args) {
+				i++
+				flags.templatePath = args[i]
+			}
+		default:
+			rest = append(rest, a)
+		}
 	}
+	return rest, flags, opts
+}
 
-	// Сообщает пользователю, куда были сохранены результаты
-	fmt.Printf("Созданы файлы по пути \"%s\":\n", exeDir)
-	fmt.Println(" - Древо папок.txt")
-	fmt.Println(" - Древо папок (Markdown).md")
-	fmt.Println(" - Древо папок (WEB).html")
-	//fmt.Println("Готово: 'Древо папок.txt', 'Древо папок (Markdown).md', 'Древо папок (WEB).html' созданы в", exeDir)
+// buildNode рекурсивно строит структуру Node для заданного пути. opts задаёт
+// правила фильтрации (--exclude/--include/--max-depth/--follow-gitignore);
+// nil означает обход без каких-либо ограничений
+func buildNode(path string, opts *buildOptions) (*Node, error) {
+	if opts == nil {
+		opts = defaultBuildOptions()
+	}
+	return buildNodeRec(path, path, opts, nil, 0)
 }
 
-// buildNode рекурсивно строит структуру Node для заданного пути
-func buildNode(path string) (*Node, error) {
+// buildNodeRec выполняет собственно рекурсивный обход. rootPath нужен, чтобы
+// вычислять относительные пути для glob-шаблонов, inherited — это накопленные
+// правила .gitignore от родительских директорий (ближайший .gitignore важнее)
+func buildNodeRec(rootPath, path string, opts *buildOptions, inherited []string, depth int) (*Node, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		// Возвращает ошибку, если информация о пути недоступна
 		return nil, err
 	}
 	node := &Node{
-		Name:  info.Name(),
-		Path:  path,
-		IsDir: info.IsDir(),
+		Name:    info.Name(),
+		Path:    path,
+		IsDir:   info.IsDir(),
+		Size:    info.Size(),
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+	}
+	if !node.IsDir {
+		return node, nil
+	}
+	node.Size = 0 // Для директорий Size — это сумма размеров потомков, а не размер самой записи
+	if opts.maxDepth >= 0 && depth >= opts.maxDepth {
+		// Достигнута максимальная глубина — содержимое директории не разворачивается
+		return node, nil
+	}
+
+	rules := inherited
+	if opts.followGitignore {
+		if local, err := loadGitignoreRules(path); err == nil && len(local) > 0 {
+			// Правила текущей директории добавляются последними, поэтому они
+			// переопределяют унаследованные — "ближайший .gitignore важнее"
+			rules = append(append([]string{}, inherited...), local...)
+		}
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		// Помечает узел ошибкой вместо того, чтобы его родитель молча его потерял
+		node.Err = err
+		return node, err
 	}
-	if node.IsDir {
-		entries, err := os.ReadDir(path)
+	sort.Slice(entries, func(i, j int) bool { return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name()) })
+	// Сортирует по имени без учета регистра, чтобы вывод выглядел аккуратно
+	for _, e := range entries {
+		childPath := filepath.Join(path, e.Name())
+		relPath, _ := filepath.Rel(rootPath, childPath)
+		relPath = filepath.ToSlash(relPath)
+
+		if isFilteredOut(e.Name(), relPath, e.IsDir(), opts) {
+			// Исключено флагом --exclude или не прошло фильтр --include
+			continue
+		}
+		if opts.followGitignore && matchRules(e.Name(), relPath, rules) {
+			// Исключено правилами .gitignore
+			continue
+		}
+
+		child, err := buildNodeRec(rootPath, childPath, opts, rules, depth+1)
+		if child == nil {
+			// До записи не удалось добраться вовсе (например, исчезла во время обхода) — пропускается
+			continue
+		}
 		if err != nil {
-			// Возвращает узел с ошибкой, если нет прав для чтения директории
-			return node, err
+			// Ошибка сохраняется на самом узле (child.Err), а не теряется при continue
+			child.Err = err
 		}
-		sort.Slice(entries, func(i, j int) bool { return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name()) })
-		// Сортирует по имени без учета регистра, чтобы вывод выглядел аккуратно
-		for _, e := range entries {
-			childPath := filepath.Join(path, e.Name())
-			child, err := buildNode(childPath)
-			if err != nil {
-				// Пропускает узел, если возникла ошибка при доступе к нему (например, symlink loop или EPERM)
-				continue
-			}
-			node.Children = append(node.Children, child)
+		if child.IsDir && child.Err == nil && len(opts.include) > 0 && len(child.Children) == 0 {
+			// --include отобрал хотя бы один файл где-то в дереве, но не в этой
+			// поддиректории — без потомков она просто шум, поэтому не показывается
+			continue
 		}
+		node.Children = append(node.Children, child)
+		node.Size += child.Size
 	}
 	return node, nil
 }
 
-// renderUnicodeTree генерирует строковое представление дерева с использованием символов Unicode
-func renderUnicodeTree(root *Node) string {
+// renderUnicodeTree генерирует строковое представление дерева с использованием символов Unicode.
+// Если showMeta установлен, после каждого имени добавляются размер, права и дата изменения.
+// Если results непуст, рядом с именами совпавших файлов добавляется число найденных --grep совпадений
+func renderUnicodeTree(root *Node, showMeta bool, results SearchResults) string {
 	var sb strings.Builder
 	sb.WriteString(root.Name + "\n")
-	renderUnicodeChildren(&sb, root, "")
+	renderUnicodeChildren(&sb, root, "", showMeta, results)
 	// Вызывает рекурсивную функцию для обработки дочерних элементов
 	return sb.String()
 }
 
 // renderUnicodeChildren рекурсивно добавляет дочерние элементы с правильными префиксами Unicode
-func renderUnicodeChildren(sb *strings.Builder, node *Node, prefix string) {
+func renderUnicodeChildren(sb *strings.Builder, node *Node, prefix string, showMeta bool, results SearchResults) {
 	for i, child := range node.Children {
 		isLast := i == len(node.Children)-1
 		if isLast {
@@ -150,101 +339,86 @@ func renderUnicodeChildren(sb *strings.Builder, node *Node, prefix string) {
 		}
 
 		if child.IsDir {
-			sb.WriteString(child.Name + "/\n")
+			sb.WriteString(child.Name + "/")
 		} else {
-			sb.WriteString(child.Name + "\n")
+			sb.WriteString(child.Name)
+		}
+		if n := len(results[child.Path]); n > 0 {
+			sb.WriteString(fmt.Sprintf(" (%d совпадений)", n))
 		}
+		if showMeta {
+			sb.WriteString(" " + metaString(child))
+		}
+		sb.WriteString(errSuffix(child))
+		sb.WriteString("\n")
 
 		if child.IsDir {
 			if isLast {
 				// Добавляет пробелы, чтобы ветка не продолжалась после последнего элемента
-				renderUnicodeChildren(sb, child, prefix+"    ")
+				renderUnicodeChildren(sb, child, prefix+"    ", showMeta, results)
 			} else {
 				// Добавляет вертикальную линию, чтобы показать продолжение ветки
-				renderUnicodeChildren(sb, child, prefix+"│   ")
+				renderUnicodeChildren(sb, child, prefix+"│   ", showMeta, results)
 			}
 		}
 	}
 }
 
-// renderMarkdown генерирует строковое представление дерева в формате Markdown
-func renderMarkdown(root *Node) string {
+// renderMarkdown генерирует строковое представление дерева в формате Markdown.
+// Если showMeta установлен, после каждого имени добавляются размер, права и дата изменения.
+// Если results непуст, рядом с именами совпавших файлов добавляется число найденных --grep совпадений
+func renderMarkdown(root *Node, showMeta bool, results SearchResults) string {
 	var sb strings.Builder
 	//sb.WriteString("# Дерево: " + root.Name + "\n\n")
-	renderMDNode(&sb, root, 0)
+	renderMDNode(&sb, root, 0, showMeta, results)
 	return sb.String()
 }
 
 // renderMDNode рекурсивно строит элементы списка Markdown
-func renderMDNode(sb *strings.Builder, n *Node, depth int) {
+func renderMDNode(sb *strings.Builder, n *Node, depth int, showMeta bool, results SearchResults) {
 	indent := strings.Repeat("  ", depth)
+	meta := ""
+	if showMeta {
+		meta = " " + metaString(n)
+	}
+	matches := ""
+	if count := len(results[n.Path]); count > 0 {
+		matches = fmt.Sprintf(" (%d совпадений)", count)
+	}
+	errs := errSuffix(n)
 	if depth == 0 {
 		// Корневой элемент оформляется как заголовок или главный элемент
-		sb.WriteString(indent + "📁 **" + n.Name + "**\n")
+		sb.WriteString(indent + "📁 **" + n.Name + "**" + matches + meta + errs + "\n")
 	} else {
 		if n.IsDir {
 			// Директории выделяются жирным шрифтом и символом папки
-			sb.WriteString(indent + "- 📁 **" + n.Name + "**\n")
+			sb.WriteString(indent + "- 📁 **" + n.Name + "**" + matches + meta + errs + "\n")
 		} else {
 			// Файлы используют символ листа и обычный текст
-			sb.WriteString(indent + "- 📄 " + n.Name + "\n")
+			sb.WriteString(indent + "- 📄 " + n.Name + matches + meta + errs + "\n")
 		}
 	}
 	for _, c := range n.Children {
-		renderMDNode(sb, c, depth+1)
+		renderMDNode(sb, c, depth+1, showMeta, results)
 	}
 }
 
-// renderHTML генерирует полную HTML страницу, отображающую дерево
-func renderHTML(root *Node) string {
+// renderHTML генерирует полную HTML страницу, отображающую дерево, прогоняя
+// её через text/template (см. templates.go). templatePath, если не пуст,
+// задаёт пользовательский шаблон (--template), иначе используется встроенный
+// templates/default.html.tmpl. Шаблон сам решает, показывать ли метаданные
+// (--show-meta) и сниппеты --grep — через функции showMeta/metaOf/matchesOf
+// из htmlFuncMap
+func renderHTML(root *Node, showMeta bool, results SearchResults, templatePath string) (string, error) {
+	tmpl, err := loadHTMLTemplate(templatePath, htmlFuncMap(showMeta, results))
+	if err != nil {
+		return "", err
+	}
 	var sb strings.Builder
-	// Записывает статический шаблон и стили
-	sb.WriteString(`<!doctype html>
-<html lang="ru">
-<head>
-<meta charset="utf-8">
-<meta name="viewport" content="width=device-width,initial-scale=1">
-<title>Древо папок (WEB)</title>
-<style>
-body { font-family: Inter, system-ui, -apple-system, "Segoe UI", Roboto, "Helvetica Neue", Arial; padding: 18px; background:#f7f7fb; color:#111 }
-.container { max-width: 1100px; margin: 0 auto; background: #fff; padding: 18px; border-radius: 10px; box-shadow: 0 6px 20px rgba(0,0,0,0.06); }
-details { margin-left: 8px; }
-summary { cursor: pointer; font-weight: 600; padding: 4px 0; }
-.file { margin-left: 22px; padding: 2px 0; font-family: monospace; }
-.meta { color:#666; font-size: 0.85em; margin-left:8px; }
-.root { text-align: center; font-weight: 800; font-size: 1.35em; margin-bottom: 6px }
-.small { font-size:0.9em; color:#666 }
-</style>
-</head>
-<body>
-<div class="container">
-<div class="root">Структура папок (можно открывать и закрывать кликами)</div>
-<hr/>
-`)
-	buildHTMLNode(&sb, root)
-	// Завершает HTML структуру
-	sb.WriteString(`
-</div>
-</body>
-</html>
-`)
-	return sb.String()
-}
-
-// buildHTMLNode рекурсивно создает структуру HTML с использованием тегов details для директорий
-func buildHTMLNode(sb *strings.Builder, n *Node) {
-	for _, c := range n.Children {
-		if c.IsDir {
-			// Использует details/summary для создания раскрывающихся секций
-			sb.WriteString("<details open>\n")
-			sb.WriteString("<summary>📁 " + escapeHTML(c.Name) + "/</summary>\n")
-			buildHTMLNode(sb, c)
-			sb.WriteString("</details>\n")
-		} else {
-			// Файлы просто добавляются как div
-			sb.WriteString("<div class=\"file\">📄 " + escapeHTML(c.Name) + "</div>\n")
-		}
+	if err := tmpl.Execute(&sb, root); err != nil {
+		return "", err
 	}
+	return sb.String(), nil
 }
 
 // escapeHTML заменяет специальные символы HTML на их сущности, чтобы избежать проблем при рендеринге
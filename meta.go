@@ -0,0 +1,38 @@
+// Copyright (c) 2025 Otto
+// Лицензия: MIT (см. LICENSE)
+
+package main
+
+import "fmt"
+
+// humanSize форматирует размер в байтах в человекочитаемую строку с
+// суффиксами B/KiB/MiB/GiB/TiB/PiB
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	suffixes := []string{"KiB", "MiB", "GiB", "TiB", "PiB"}
+	return fmt.Sprintf("%.1f %s", float64(size)/float64(div), suffixes[exp])
+}
+
+// metaString формирует строку метаданных узла (размер, права, дата изменения)
+// для отображения в renderUnicodeTree/renderMarkdown за флагом --show-meta
+func metaString(n *Node) string {
+	return fmt.Sprintf("[%s, %s, %s]", humanSize(n.Size), n.Mode.String(), n.ModTime.Format("2006-01-02 15:04"))
+}
+
+// errSuffix возвращает отметку "⚠ ошибка чтения" для узла, директорию
+// которого не удалось прочитать (Node.Err != nil), иначе — пустую строку.
+// Без неё такие директории выглядели бы как пустые, а не как недоступные
+func errSuffix(n *Node) string {
+	if n.Err == nil {
+		return ""
+	}
+	return fmt.Sprintf(" ⚠ ошибка чтения: %v", n.Err)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2025 Otto
+// Лицензия: MIT (см. LICENSE)
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Snippet описывает одно совпадение --grep внутри файла вместе с несколькими
+// строками контекста вокруг него — в духе "Find Usages" из wide
+type Snippet struct {
+	Path     string   // Путь к файлу, в котором найдено совпадение
+	Line     int      // Номер строки совпадения (с 1)
+	Col      int      // Номер столбца начала совпадения (с 1)
+	Contents []string // Несколько строк контекста вокруг совпадения, включая саму строку
+}
+
+// SearchResults сопоставляет путь файла со списком найденных в нём совпадений
+type SearchResults map[string][]Snippet
+
+// searchTree обходит уже построенное дерево (а значит, учитывает все
+// примененные --exclude/--include/.gitignore фильтры) и ищет в файлах
+// совпадения с регулярным выражением re. Если exts непуст, проверяются
+// только файлы с указанными расширениями (например, []string{".go", ".md"})
+func searchTree(root *Node, re *regexp.Regexp, exts []string) SearchResults {
+	results := make(SearchResults)
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if n.IsDir {
+			for _, c := range n.Children {
+				walk(c)
+			}
+			return
+		}
+		if len(exts) > 0 && !extMatches(n.Name, exts) {
+			return
+		}
+		snippets, err := searchFile(n.Path, re)
+		if err != nil || len(snippets) == 0 {
+			// Пропускает файлы, которые не удалось прочитать (например, недоступные), и файлы без совпадений
+			return
+		}
+		results[n.Path] = snippets
+	}
+	walk(root)
+	return results
+}
+
+// extMatches сообщает, входит ли расширение имени name в список exts
+// (сравнение без учёта регистра)
+func extMatches(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exts {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// contextLines — сколько строк контекста добавляется до и после совпадения
+const contextLines = 2
+
+// searchFile построчно ищет в файле совпадения с re и возвращает по одному
+// Snippet на каждую строку с совпадением, с контекстом в ±contextLines строк
+func searchFile(path string, re *regexp.Regexp) ([]Snippet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" && strings.HasSuffix(string(data), "\n") {
+		// Файл оканчивается на "\n" — последний элемент от Split это не строка,
+		// а фантомный "хвост" после последнего перевода строки, его отбрасывает
+		lines = lines[:len(lines)-1]
+	}
+
+	var snippets []Snippet
+	for i, line := range lines {
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i + contextLines
+		if end >= len(lines) {
+			end = len(lines) - 1
+		}
+		snippets = append(snippets, Snippet{
+			Path:     path,
+			Line:     i + 1,
+			Col:      loc[0] + 1,
+			Contents: append([]string{}, lines[start:end+1]...),
+		})
+	}
+	return snippets, nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2025 Otto
+// Лицензия: MIT (см. LICENSE)
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// freshFor определяет, сколько времени закэшированное дерево считается
+// актуальным, прежде чем сервер попытается перестроить его заново
+const freshFor = 2 * time.Second
+
+// minRebuildDelay и maxRebuildDelay задают границы экспоненциального отката
+// между повторными попытками перестроения дерева после ошибки
+const (
+	minRebuildDelay = 1 * time.Second
+	maxRebuildDelay = 30 * time.Second
+)
+
+// treeCache хранит последнее успешно построенное дерево и решает, когда его
+// нужно перестраивать. Идея позаимствована у RWValue/delayTime из godoc:
+// вместо перестроения дерева на каждый запрос сервер отдаёт закэшированную
+// версию и не обновляет её чаще, чем раз в freshFor, а при ошибке
+// перестроения откладывает следующую попытку по экспоненте
+type treeCache struct {
+	mu      sync.Mutex
+	dirPath string
+	opts    *buildOptions
+
+	root    *Node
+	builtAt time.Time
+	nextTry time.Time
+	delay   time.Duration
+	lastErr error // последняя ошибка сборки, если ни одна сборка ещё не завершилась успешно
+}
+
+// newTreeCache создаёт пустой кэш дерева для указанного пути и опций обхода
+func newTreeCache(dirPath string, opts *buildOptions) *treeCache {
+	return &treeCache{dirPath: dirPath, opts: opts, delay: minRebuildDelay}
+}
+
+// get возвращает актуальное дерево вместе с временем его построения,
+// перестраивая его при необходимости
+func (c *treeCache) get() (*Node, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(c.nextTry) {
+		if c.root != nil {
+			// Кэш ещё свежий (или идёт откат после недавней ошибки) — отдаёт его как есть
+			return c.root, c.builtAt, nil
+		}
+		// Ни одной успешной сборки ещё не было, но откат после прошлой ошибки
+		// ещё не истёк — не долбит файловую систему повторными попытками
+		return nil, time.Time{}, c.lastErr
+	}
+
+	root, err := buildNode(c.dirPath, c.opts)
+	if err != nil {
+		c.lastErr = err
+		c.nextTry = now.Add(c.delay)
+		// Увеличивает задержку перед следующей попыткой (экспоненциальный откат)
+		c.delay *= 2
+		if c.delay > maxRebuildDelay {
+			c.delay = maxRebuildDelay
+		}
+		if c.root != nil {
+			// Есть прошлая успешная версия — отдаёт её вместо ошибки 500
+			return c.root, c.builtAt, nil
+		}
+		return nil, time.Time{}, err
+	}
+
+	c.root = root
+	c.builtAt = now
+	c.delay = minRebuildDelay
+	c.nextTry = now.Add(freshFor)
+	return c.root, c.builtAt, nil
+}
+
+// runServer запускает HTTP-сервер, который строит дерево папок по запросу
+// (с учётом кэша treeCache) вместо того, чтобы один раз записать файлы на диск.
+// Это позволяет просматривать меняющуюся файловую систему в браузере без
+// повторного запуска CLI
+func runServer(addr string, dirPath string, opts *buildOptions, flags *cliFlags) error {
+	cache := newTreeCache(dirPath, opts)
+
+	// searchResultsFor возвращает совпадения --grep для root, если флаг задан
+	searchResultsFor := func(root *Node) SearchResults {
+		if flags.grep == nil {
+			return nil
+		}
+		return searchTree(root, flags.grep, flags.grepExts)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", serveTree(cache, "text/html; charset=utf-8", func(root *Node) (string, error) {
+		return renderHTML(root, flags.showMeta, searchResultsFor(root), flags.templatePath)
+	}))
+	mux.HandleFunc("/tree.txt", serveTree(cache, "text/plain; charset=utf-8", func(root *Node) (string, error) {
+		return renderUnicodeTree(root, flags.showMeta, searchResultsFor(root)), nil
+	}))
+	mux.HandleFunc("/tree.md", serveTree(cache, "text/markdown; charset=utf-8", func(root *Node) (string, error) {
+		return renderMarkdown(root, flags.showMeta, searchResultsFor(root)), nil
+	}))
+	mux.HandleFunc("/tree.json", serveTree(cache, "application/json; charset=utf-8", func(root *Node) (string, error) {
+		return renderJSON(root), nil
+	}))
+
+	log.Printf("FolderTree: сервер запущен на %s (директория: %s)\n", addr, dirPath)
+	return http.ListenAndServe(addr, mux)
+}
+
+// serveTree строит обработчик, который достаёт актуальное дерево из кэша и
+// отдаёт его в виде, подготовленном функцией render (Unicode/Markdown/HTML/JSON)
+func serveTree(cache *treeCache, contentType string, render func(*Node) (string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/tree.txt" && r.URL.Path != "/tree.md" && r.URL.Path != "/tree.json" {
+			http.NotFound(w, r)
+			return
+		}
+		root, builtAt, err := cache.get()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Ошибка построения дерева: %v", err), http.StatusInternalServerError)
+			return
+		}
+		text, err := render(root)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Ошибка рендеринга: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Last-Modified", builtAt.UTC().Format(http.TimeFormat))
+		fmt.Fprint(w, text)
+	}
+}
+
@@ -0,0 +1,51 @@
+// Copyright (c) 2025 Otto
+// Лицензия: MIT (см. LICENSE)
+
+package main
+
+import (
+	"embed"
+	"path/filepath"
+	"text/template"
+)
+
+//go:embed templates/default.html.tmpl
+var embeddedTemplates embed.FS
+
+// defaultTemplateName — имя встроенного шаблона HTML-страницы по умолчанию
+const defaultTemplateName = "default.html.tmpl"
+
+// htmlFuncMap строит набор функций, доступных HTML-шаблону: isDir/basename/ext/
+// humanSize/escapeHTML — как того требует --template, плюс несколько функций,
+// замыкающих showMeta и results, чтобы шаблон мог отображать --show-meta и
+// --grep, не получая их отдельным аргументом
+func htmlFuncMap(showMeta bool, results SearchResults) template.FuncMap {
+	return template.FuncMap{
+		"isDir":      func(n *Node) bool { return n.IsDir },
+		"basename":   filepath.Base,
+		"ext":        filepath.Ext,
+		"humanSize":  humanSize,
+		"escapeHTML": escapeHTML,
+		"showMeta":   func() bool { return showMeta },
+		"metaOf":     metaString,
+		"errOf":      errSuffix,
+		"matchesOf":  func(n *Node) []Snippet { return results[n.Path] },
+		"startLine": func(s Snippet) int {
+			start := s.Line - contextLines
+			if start < 1 {
+				start = 1
+			}
+			return start
+		},
+		"add": func(a, b int) int { return a + b },
+	}
+}
+
+// loadHTMLTemplate разбирает шаблон страницы: файл, указанный флагом
+// --template, если он есть, иначе встроенный шаблон templates/default.html.tmpl
+func loadHTMLTemplate(templatePath string, funcs template.FuncMap) (*template.Template, error) {
+	if templatePath == "" {
+		return template.New(defaultTemplateName).Funcs(funcs).ParseFS(embeddedTemplates, "templates/"+defaultTemplateName)
+	}
+	return template.New(filepath.Base(templatePath)).Funcs(funcs).ParseFiles(templatePath)
+}